@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// Stage is a position in the requisition approval pipeline.
+type Stage string
+
+const (
+	StageSubmitted     Stage = "Submitted"
+	StageManagerReview Stage = "ManagerReview"
+	StageFinanceReview Stage = "FinanceReview"
+	StageApproved      Stage = "Approved"
+	StageRejected      Stage = "Rejected"
+	StagePosted        Stage = "Posted"
+)
+
+// nextStage returns the stage a draft advances to on approval, or "" if
+// the pipeline has nowhere further to go.
+func (s Stage) nextStage() Stage {
+	switch s {
+	case StageSubmitted:
+		return StageManagerReview
+	case StageManagerReview:
+		return StageFinanceReview
+	case StageFinanceReview:
+		return StageApproved
+	default:
+		return ""
+	}
+}
+
+// RequisitionDraft is the in-app record of a requisition as it moves
+// through approval, before (and after) it is posted to NetSuite.
+type RequisitionDraft struct {
+	ID          string                   `json:"id"`
+	Stage       Stage                    `json:"stage"`
+	RequestorID string                   `json:"requestorId"`
+	Approvers   map[Stage]string         `json:"approvers"` // stage -> assigned approver employee ID
+	Request     CreateRequisitionRequest `json:"request"`
+	NetSuiteID  string                   `json:"netsuiteId,omitempty"`
+	CreatedAt   time.Time                `json:"createdAt"`
+	UpdatedAt   time.Time                `json:"updatedAt"`
+}
+
+// AuditEntry is one link in a requisition's signed audit trail. Hash
+// chains the entry to the one before it, so rewriting history requires
+// recomputing every signature after the tampered point.
+type AuditEntry struct {
+	Seq        int       `json:"seq"`
+	PrevHash   string    `json:"prevHash"`
+	Stage      Stage     `json:"stage"`
+	ApproverID string    `json:"approverId"`
+	Comment    string    `json:"comment"`
+	Timestamp  time.Time `json:"timestamp"`
+	Hash       string    `json:"hash"`
+}
+
+var (
+	draftsBucket = []byte("drafts")
+	auditBucket  = []byte("audit")
+)
+
+// ApprovalManager owns the approval workflow: drafts, their hash-chained
+// audit logs, and the final hand-off to NetSuite once a draft clears the
+// pipeline.
+type ApprovalManager struct {
+	db       *bbolt.DB
+	netsuite *NetSuiteClient
+	hmacKey  []byte
+}
+
+// NewApprovalManager opens (creating if needed) the BoltDB file at dbPath
+// and prepares the buckets the manager needs.
+func NewApprovalManager(dbPath string, netsuite *NetSuiteClient, hmacKey []byte) (*ApprovalManager, error) {
+	db, err := bbolt.Open(dbPath, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open approval db: %w", err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(draftsBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(auditBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init approval buckets: %w", err)
+	}
+
+	return &ApprovalManager{db: db, netsuite: netsuite, hmacKey: hmacKey}, nil
+}
+
+// CreateDraft records a new requisition and appends the genesis audit
+// entry at StageSubmitted. Submitting isn't itself an approval step, so
+// the draft starts gated at StageManagerReview - the first stage with an
+// assigned approver - rather than requiring a no-op approval to leave
+// StageSubmitted.
+func (m *ApprovalManager) CreateDraft(req CreateRequisitionRequest, requestorID string, approvers map[Stage]string) (*RequisitionDraft, error) {
+	now := time.Now()
+	draft := &RequisitionDraft{
+		Stage:       StageManagerReview,
+		RequestorID: requestorID,
+		Approvers:   approvers,
+		Request:     req,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err := m.db.Update(func(tx *bbolt.Tx) error {
+		id, err := tx.Bucket(draftsBucket).NextSequence()
+		if err != nil {
+			return err
+		}
+		draft.ID = strconv.FormatUint(id, 10)
+
+		if err := m.putDraft(tx, draft); err != nil {
+			return err
+		}
+		_, err = m.appendAudit(tx, draft.ID, StageSubmitted, requestorID, "requisition submitted")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// Approve advances reqID to the next stage, recording who approved it and
+// why. Once the pipeline is exhausted the draft moves to StagePosted and
+// is submitted to NetSuite.
+func (m *ApprovalManager) Approve(reqID, approverID, comment string) (*RequisitionDraft, error) {
+	var draft *RequisitionDraft
+	err := m.db.Update(func(tx *bbolt.Tx) error {
+		d, err := m.getDraft(tx, reqID)
+		if err != nil {
+			return err
+		}
+		if d.Stage == StageApproved || d.Stage == StagePosted || d.Stage == StageRejected {
+			return fmt.Errorf("requisition %s is already at stage %s", reqID, d.Stage)
+		}
+		if d.Approvers[d.Stage] != approverID {
+			return fmt.Errorf("%s is not the assigned approver for requisition %s at stage %s", approverID, reqID, d.Stage)
+		}
+
+		next := d.Stage.nextStage()
+		if next == "" {
+			return fmt.Errorf("requisition %s has no further approval stage", reqID)
+		}
+
+		d.Stage = next
+		d.UpdatedAt = time.Now()
+		if err := m.putDraft(tx, d); err != nil {
+			return err
+		}
+		if _, err := m.appendAudit(tx, reqID, next, approverID, comment); err != nil {
+			return err
+		}
+		draft = d
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if draft.Stage != StageApproved {
+		return draft, nil
+	}
+	return m.post(draft)
+}
+
+// Reject moves reqID to StageRejected. A comment is required so the
+// audit trail always explains why a requisition stopped moving.
+func (m *ApprovalManager) Reject(reqID, approverID, comment string) (*RequisitionDraft, error) {
+	if strings.TrimSpace(comment) == "" {
+		return nil, errors.New("a comment is required to reject a requisition")
+	}
+
+	var draft *RequisitionDraft
+	err := m.db.Update(func(tx *bbolt.Tx) error {
+		d, err := m.getDraft(tx, reqID)
+		if err != nil {
+			return err
+		}
+		if d.Stage == StageApproved || d.Stage == StagePosted || d.Stage == StageRejected {
+			return fmt.Errorf("requisition %s is already at stage %s", reqID, d.Stage)
+		}
+		if d.Approvers[d.Stage] != approverID {
+			return fmt.Errorf("%s is not the assigned approver for requisition %s at stage %s", approverID, reqID, d.Stage)
+		}
+
+		d.Stage = StageRejected
+		d.UpdatedAt = time.Now()
+		if err := m.putDraft(tx, d); err != nil {
+			return err
+		}
+		_, err = m.appendAudit(tx, reqID, StageRejected, approverID, comment)
+		draft = d
+		return err
+	})
+	return draft, err
+}
+
+// post calls out to NetSuite now that a draft has cleared every approval
+// stage, then records the Posted transition and the resulting tranId.
+func (m *ApprovalManager) post(draft *RequisitionDraft) (*RequisitionDraft, error) {
+	payload, err := m.netsuite.Call(context.Background(), http.MethodPost, nil, draft.Request)
+	if err != nil {
+		return draft, fmt.Errorf("post to netsuite: %w", err)
+	}
+
+	var response struct {
+		ID     string `json:"id"`
+		TranID string `json:"tranId"`
+	}
+	_ = json.Unmarshal(payload, &response)
+
+	err = m.db.Update(func(tx *bbolt.Tx) error {
+		draft.Stage = StagePosted
+		draft.NetSuiteID = response.TranID
+		draft.UpdatedAt = time.Now()
+		if err := m.putDraft(tx, draft); err != nil {
+			return err
+		}
+		_, err := m.appendAudit(tx, draft.ID, StagePosted, "system", "posted to NetSuite as "+response.TranID)
+		return err
+	})
+	return draft, err
+}
+
+// PendingFor lists drafts awaiting action from approverID at their
+// current stage.
+func (m *ApprovalManager) PendingFor(approverID string) ([]*RequisitionDraft, error) {
+	var pending []*RequisitionDraft
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(draftsBucket).ForEach(func(k, v []byte) error {
+			var d RequisitionDraft
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if d.Stage == StageApproved || d.Stage == StageRejected || d.Stage == StagePosted {
+				return nil
+			}
+			if d.Approvers[d.Stage] == approverID {
+				pending = append(pending, &d)
+			}
+			return nil
+		})
+	})
+	return pending, err
+}
+
+// appendAudit writes the next hash-chained entry for reqID and returns it.
+func (m *ApprovalManager) appendAudit(tx *bbolt.Tx, reqID string, stage Stage, approverID, comment string) (*AuditEntry, error) {
+	bucket, err := tx.Bucket(auditBucket).CreateBucketIfNotExists([]byte(reqID))
+	if err != nil {
+		return nil, err
+	}
+
+	prevHash := ""
+	if cursor := bucket.Cursor(); true {
+		k, v := cursor.Last()
+		if k != nil {
+			var prev AuditEntry
+			if err := json.Unmarshal(v, &prev); err != nil {
+				return nil, err
+			}
+			prevHash = prev.Hash
+		}
+	}
+
+	entry := &AuditEntry{
+		Seq:        int(bucket.Stats().KeyN) + 1,
+		PrevHash:   prevHash,
+		Stage:      stage,
+		ApproverID: approverID,
+		Comment:    comment,
+		Timestamp:  time.Now(),
+	}
+	entry.Hash = m.signEntry(entry)
+
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%08d", entry.Seq)
+	return entry, bucket.Put([]byte(key), raw)
+}
+
+// signEntry computes HMAC-SHA256 over (prevHash || stage || approverID ||
+// timestamp || comment), which is what makes the audit log a verifiable
+// hash chain rather than a plain append-only list.
+func (m *ApprovalManager) signEntry(e *AuditEntry) string {
+	mac := hmac.New(sha256.New, m.hmacKey)
+	mac.Write([]byte(e.PrevHash))
+	mac.Write([]byte(e.Stage))
+	mac.Write([]byte(e.ApproverID))
+	mac.Write([]byte(e.Timestamp.UTC().Format(time.RFC3339Nano)))
+	mac.Write([]byte(e.Comment))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyChain recomputes every signature in reqID's audit log and
+// confirms each entry's prevHash matches the hash of its predecessor. It
+// returns false (with no error) if the chain has been tampered with.
+func (m *ApprovalManager) VerifyChain(reqID string) (bool, error) {
+	ok := true
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(auditBucket).Bucket([]byte(reqID))
+		if bucket == nil {
+			return fmt.Errorf("no audit log for requisition %s", reqID)
+		}
+
+		prevHash := ""
+		return bucket.ForEach(func(k, v []byte) error {
+			var entry AuditEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if entry.PrevHash != prevHash {
+				ok = false
+			}
+			want := m.signEntry(&entry)
+			if !hmac.Equal([]byte(want), []byte(entry.Hash)) {
+				ok = false
+			}
+			prevHash = entry.Hash
+			return nil
+		})
+	})
+	return ok, err
+}
+
+// StartNightlyVerifier launches a goroutine that verifies every
+// requisition's audit chain once per interval, logging any chain that fails
+// verification so tampering is caught even if no one calls VerifyChain
+// directly.
+func (m *ApprovalManager) StartNightlyVerifier(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			m.verifyAll()
+		}
+	}()
+}
+
+func (m *ApprovalManager) verifyAll() {
+	var ids []string
+	err := m.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(auditBucket).ForEach(func(k, v []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("audit verifier: list requisitions: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		ok, err := m.VerifyChain(id)
+		if err != nil {
+			log.Printf("audit verifier: requisition %s: %v", id, err)
+			continue
+		}
+		if !ok {
+			log.Printf("audit verifier: ALERT - requisition %s failed hash chain verification", id)
+		}
+	}
+}
+
+func (m *ApprovalManager) putDraft(tx *bbolt.Tx, d *RequisitionDraft) error {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(draftsBucket).Put([]byte(d.ID), raw)
+}
+
+func (m *ApprovalManager) getDraft(tx *bbolt.Tx, id string) (*RequisitionDraft, error) {
+	raw := tx.Bucket(draftsBucket).Get([]byte(id))
+	if raw == nil {
+		return nil, fmt.Errorf("requisition %s not found", id)
+	}
+	var d RequisitionDraft
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// --- HTTP handlers ---
+
+func (s *Server) handlePendingApprovals(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		writeError(w, http.StatusServiceUnavailable, "approval workflow is not configured yet.")
+		return
+	}
+
+	approverID, err := s.resolveEmployeeID(r.Context(), sessionEmail(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pending, err := s.approvals.PendingFor(approverID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+// handleApproveRequisition handles POST /api/approvals/{id}/approve.
+func (s *Server) handleApproveRequisition(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		writeError(w, http.StatusServiceUnavailable, "approval workflow is not configured yet.")
+		return
+	}
+
+	id, ok := approvalIDFromPath(r.URL.Path, "approve")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "malformed approval path")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form submission")
+		return
+	}
+	approverID, err := s.resolveEmployeeID(r.Context(), sessionEmail(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	draft, err := s.approvals.Approve(id, approverID, strings.TrimSpace(r.FormValue("comment")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// handleRejectRequisition handles POST /api/approvals/{id}/reject. A
+// comment is mandatory - it becomes the reason captured in the audit log.
+func (s *Server) handleRejectRequisition(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		writeError(w, http.StatusServiceUnavailable, "approval workflow is not configured yet.")
+		return
+	}
+
+	id, ok := approvalIDFromPath(r.URL.Path, "reject")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "malformed approval path")
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid form submission")
+		return
+	}
+	approverID, err := s.resolveEmployeeID(r.Context(), sessionEmail(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	comment := strings.TrimSpace(r.FormValue("comment"))
+	if comment == "" {
+		writeError(w, http.StatusBadRequest, "comment is required to reject a requisition")
+		return
+	}
+
+	draft, err := s.approvals.Reject(id, approverID, comment)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(draft)
+}
+
+// handleVerifyChain handles GET /api/approvals/{id}/verify.
+func (s *Server) handleVerifyChain(w http.ResponseWriter, r *http.Request) {
+	if s.approvals == nil {
+		writeError(w, http.StatusServiceUnavailable, "approval workflow is not configured yet.")
+		return
+	}
+
+	id, ok := approvalIDFromPath(r.URL.Path, "verify")
+	if !ok {
+		writeError(w, http.StatusBadRequest, "malformed approval path")
+		return
+	}
+
+	valid, err := s.approvals.VerifyChain(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"valid": valid})
+}
+
+// approvalIDFromPath extracts {id} from /api/approvals/{id}/{suffix}.
+func approvalIDFromPath(urlPath, suffix string) (string, bool) {
+	trimmed := strings.TrimPrefix(urlPath, "/api/approvals/")
+	trimmed = strings.TrimSuffix(trimmed, "/"+suffix)
+	if trimmed == "" || strings.Contains(trimmed, "/") {
+		return "", false
+	}
+	return trimmed, true
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"go.etcd.io/bbolt"
+)
+
+// TestVerifyChain_DetectsTampering confirms VerifyChain catches an audit
+// entry edited directly in storage, bypassing appendAudit's signing.
+func TestVerifyChain_DetectsTampering(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "approvals.db")
+	m, err := NewApprovalManager(dbPath, nil, []byte("test-hmac-key"))
+	if err != nil {
+		t.Fatalf("NewApprovalManager: %v", err)
+	}
+
+	approvers := map[Stage]string{
+		StageManagerReview: "manager-1",
+		StageFinanceReview: "finance-1",
+	}
+	draft, err := m.CreateDraft(CreateRequisitionRequest{}, "employee-1", approvers)
+	if err != nil {
+		t.Fatalf("CreateDraft: %v", err)
+	}
+	if _, err := m.Approve(draft.ID, "manager-1", "looks good"); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+
+	ok, err := m.VerifyChain(draft.ID)
+	if err != nil {
+		t.Fatalf("VerifyChain: %v", err)
+	}
+	if !ok {
+		t.Fatal("expected an untampered chain to verify")
+	}
+
+	// Rewrite the genesis entry's comment without recomputing its
+	// signature, simulating an attacker editing the bbolt file directly.
+	err = m.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(auditBucket).Bucket([]byte(draft.ID))
+		key, value := bucket.Cursor().First()
+		var entry AuditEntry
+		if err := json.Unmarshal(value, &entry); err != nil {
+			return err
+		}
+		entry.Comment = "requisition secretly pre-approved"
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, raw)
+	})
+	if err != nil {
+		t.Fatalf("tamper with audit entry: %v", err)
+	}
+
+	ok, err = m.VerifyChain(draft.ID)
+	if err != nil {
+		t.Fatalf("VerifyChain after tamper: %v", err)
+	}
+	if ok {
+		t.Fatal("expected tampering to be detected")
+	}
+}
+
+// TestApprove_RejectsMismatchedApprover confirms an approver assigned to
+// a different stage can't advance someone else's requisition.
+func TestApprove_RejectsMismatchedApprover(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "approvals.db")
+	m, err := NewApprovalManager(dbPath, nil, []byte("test-hmac-key"))
+	if err != nil {
+		t.Fatalf("NewApprovalManager: %v", err)
+	}
+
+	approvers := map[Stage]string{
+		StageManagerReview: "manager-1",
+		StageFinanceReview: "finance-1",
+	}
+	draft, err := m.CreateDraft(CreateRequisitionRequest{}, "employee-1", approvers)
+	if err != nil {
+		t.Fatalf("CreateDraft: %v", err)
+	}
+
+	if _, err := m.Approve(draft.ID, "someone-else", "approving on your behalf"); err == nil {
+		t.Fatal("expected approval by an unassigned approver to be rejected")
+	}
+}
+
+// TestApprove_HandsOffToFinanceAfterOneManagerApproval confirms a single
+// manager approval moves a freshly created draft straight to finance's
+// queue, rather than requiring the manager to approve twice before
+// finance ever sees it.
+func TestApprove_HandsOffToFinanceAfterOneManagerApproval(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "approvals.db")
+	m, err := NewApprovalManager(dbPath, nil, []byte("test-hmac-key"))
+	if err != nil {
+		t.Fatalf("NewApprovalManager: %v", err)
+	}
+
+	approvers := map[Stage]string{
+		StageManagerReview: "manager-1",
+		StageFinanceReview: "finance-1",
+	}
+	draft, err := m.CreateDraft(CreateRequisitionRequest{}, "employee-1", approvers)
+	if err != nil {
+		t.Fatalf("CreateDraft: %v", err)
+	}
+
+	draft, err = m.Approve(draft.ID, "manager-1", "looks good")
+	if err != nil {
+		t.Fatalf("Approve by manager: %v", err)
+	}
+	if draft.Stage != StageFinanceReview {
+		t.Fatalf("expected draft to move to StageFinanceReview after one manager approval, got %s", draft.Stage)
+	}
+
+	pending, err := m.PendingFor("manager-1")
+	if err != nil {
+		t.Fatalf("PendingFor manager: %v", err)
+	}
+	for _, d := range pending {
+		if d.ID == draft.ID {
+			t.Fatal("draft should no longer be pending for the manager after their approval")
+		}
+	}
+
+	pending, err = m.PendingFor("finance-1")
+	if err != nil {
+		t.Fatalf("PendingFor finance: %v", err)
+	}
+	found := false
+	for _, d := range pending {
+		if d.ID == draft.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected draft to be pending for finance after the manager's approval")
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Identity is what an AuthProvider hands back once a sign-in completes.
+type Identity struct {
+	Email string
+}
+
+// AuthProvider abstracts the OAuth2/OIDC dance so a "dev" provider can
+// stand in for a real IdP when contributors don't have one to point at.
+type AuthProvider interface {
+	// LoginURL returns where to send the browser to start a sign-in,
+	// with state used to guard the subsequent callback.
+	LoginURL(state string) string
+	// Exchange turns the callback's authorization code into a verified
+	// Identity.
+	Exchange(ctx context.Context, code string) (Identity, error)
+}
+
+// NewAuthProviderFromConfig picks an AuthProvider based on the config's
+// auth.provider ("oidc", the default, or "dev" for local testing without
+// an IdP).
+func NewAuthProviderFromConfig(ctx context.Context, cfg AuthConfig) (AuthProvider, error) {
+	if cfg.Provider == "dev" {
+		email := cfg.DevEmail
+		if email == "" {
+			email = "dev@example.com"
+		}
+		return &devProvider{email: email}, nil
+	}
+	return newOIDCProviderFromConfig(ctx, cfg)
+}
+
+// oidcProvider authenticates against a real OpenID Connect issuer.
+type oidcProvider struct {
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func newOIDCProviderFromConfig(ctx context.Context, cfg AuthConfig) (AuthProvider, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.ClientSecret == "" || cfg.RedirectURL == "" {
+		return nil, errors.New("auth.issuer, auth.clientId, auth.clientSecret and auth.redirectUrl must all be set")
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover oidc issuer: %w", err)
+	}
+
+	return &oidcProvider{
+		config: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+func (p *oidcProvider) LoginURL(state string) string {
+	return p.config.AuthCodeURL(state)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code string) (Identity, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return Identity{}, fmt.Errorf("exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("oidc response did not include an id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("verify id_token: %w", err)
+	}
+
+	var claims struct {
+		Email string `json:"email"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("parse id_token claims: %w", err)
+	}
+	if claims.Email == "" {
+		return Identity{}, errors.New("id_token did not include an email claim")
+	}
+
+	return Identity{Email: claims.Email}, nil
+}
+
+// devProvider is a no-IdP stand-in for local development: every sign-in
+// succeeds as the configured auth.devEmail (dev@example.com when unset).
+type devProvider struct {
+	email string
+}
+
+func (p *devProvider) LoginURL(state string) string {
+	return "/auth/callback?code=dev&state=" + url.QueryEscape(state)
+}
+
+func (p *devProvider) Exchange(ctx context.Context, code string) (Identity, error) {
+	return Identity{Email: p.email}, nil
+}
+
+// SessionStore issues and verifies signed session cookies carrying the
+// authenticated user's email, using the same HMAC-SHA256 primitive as
+// the approval audit chain.
+type SessionStore struct {
+	hmacKey    []byte
+	cookieName string
+	ttl        time.Duration
+	secure     bool
+}
+
+// NewSessionStore builds a SessionStore signing cookies with key. secure
+// controls the cookie's Secure attribute - it must be false for the dev
+// provider, which runs the app over plain HTTP with no IdP, or a
+// standards-compliant client will never send the cookie back and
+// RequireAuth will redirect to /auth/login forever.
+func NewSessionStore(key []byte, secure bool) *SessionStore {
+	return &SessionStore{hmacKey: key, cookieName: "po_session", ttl: 12 * time.Hour, secure: secure}
+}
+
+// Issue sets a signed session cookie on w identifying email.
+func (s *SessionStore) Issue(w http.ResponseWriter, email string) {
+	expires := time.Now().Add(s.ttl)
+	payload := email + "|" + strconv.FormatInt(expires.Unix(), 10)
+	value := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.sign(payload)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Email returns the authenticated email carried by r's session cookie,
+// or ok=false if there is no cookie, the signature doesn't match, or it
+// has expired.
+func (s *SessionStore) Email(r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(s.cookieName)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", false
+	}
+	payload := string(rawPayload)
+
+	if !hmac.Equal([]byte(s.sign(payload)), []byte(parts[1])) {
+		return "", false
+	}
+
+	fields := strings.SplitN(payload, "|", 2)
+	if len(fields) != 2 {
+		return "", false
+	}
+
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false
+	}
+
+	return fields[0], true
+}
+
+// Clear removes the session cookie on w, signing the user out.
+func (s *SessionStore) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: s.cookieName, Value: "", Path: "/", MaxAge: -1})
+}
+
+func (s *SessionStore) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.hmacKey)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type sessionEmailKey struct{}
+
+// sessionEmail returns the email RequireAuth attached to r's context, or
+// "" if RequireAuth hasn't run (or authentication is disabled).
+func sessionEmail(r *http.Request) string {
+	email, _ := r.Context().Value(sessionEmailKey{}).(string)
+	return email
+}
+
+// RequireAuth rejects requests without a valid session cookie, redirecting
+// page loads to /auth/login and returning a JSON error for /api/* calls.
+// Authenticated requests carry the user's email in their context,
+// retrievable with sessionEmail.
+func (s *Server) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		email, ok := s.sessions.Email(r)
+		if !ok {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeError(w, http.StatusUnauthorized, "sign in required")
+				return
+			}
+			http.Redirect(w, r, "/auth/login", http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionEmailKey{}, email)))
+	})
+}
+
+const oauthStateCookie = "oauth_state"
+
+// handleLogin redirects to the configured AuthProvider's consent screen,
+// stashing an anti-CSRF state value in a short-lived cookie.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	state := randomNonce(32)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   300,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, s.auth.LoginURL(state), http.StatusFound)
+}
+
+// handleAuthCallback completes the sign-in: verifies the state cookie,
+// exchanges the code for an Identity, and issues a session cookie.
+func (s *Server) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(oauthStateCookie)
+	if err != nil || r.URL.Query().Get("state") != stateCookie.Value {
+		writeError(w, http.StatusBadRequest, "invalid oauth state")
+		return
+	}
+
+	identity, err := s.auth.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "sign-in failed: "+err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Value: "", Path: "/", MaxAge: -1})
+	s.sessions.Issue(w, identity.Email)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	s.sessions.Clear(w)
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// resolveEmployeeID maps an authenticated email to its NetSuite employee
+// ID, reusing the employee cache populated by handleEmployees when it's
+// still fresh.
+func (s *Server) resolveEmployeeID(ctx context.Context, email string) (string, error) {
+	if email == "" {
+		return "", errors.New("no authenticated user on this request")
+	}
+
+	s.cacheMu.RLock()
+	cached := s.employeeCache
+	fresh := cached != nil && time.Since(s.employeeCacheTime) < s.config.Get().cacheTTL("employees", cacheDuration)
+	s.cacheMu.RUnlock()
+
+	if fresh {
+		return employeeIDByEmail(cached, email)
+	}
+
+	if s.netsuite == nil {
+		return "", errors.New("NetSuite restlet is not configured yet.")
+	}
+
+	payload, err := s.netsuite.CallWithOptions(ctx, http.MethodGet, map[string]string{
+		"action": "employees",
+	}, nil, CacheRefreshCallOptions())
+	if err != nil {
+		return "", err
+	}
+
+	s.cacheMu.Lock()
+	s.employeeCache = payload
+	s.employeeCacheTime = time.Now()
+	s.cacheMu.Unlock()
+
+	return employeeIDByEmail(payload, email)
+}
+
+func employeeIDByEmail(payload []byte, email string) (string, error) {
+	var employees []Employee
+	if err := json.Unmarshal(payload, &employees); err != nil {
+		return "", err
+	}
+	for _, e := range employees {
+		if strings.EqualFold(e.Email, email) {
+			return e.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no NetSuite employee found for %s", email)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestRequireAuth_RejectsMissingSession confirms API routes get a JSON
+// 401 rather than running the wrapped handler when there's no session.
+func TestRequireAuth_RejectsMissingSession(t *testing.T) {
+	s := &Server{sessions: NewSessionStore([]byte("test-session-key"), true)}
+	called := false
+	handler := s.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/approvals/pending", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if called {
+		t.Fatal("next handler should not run without a valid session")
+	}
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an unauthenticated API request, got %d", rec.Code)
+	}
+}
+
+// TestRequireAuth_RedirectsPageRequests confirms page routes get sent to
+// sign-in instead of a bare 401.
+func TestRequireAuth_RedirectsPageRequests(t *testing.T) {
+	s := &Server{sessions: NewSessionStore([]byte("test-session-key"), true)}
+	handler := s.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("next handler should not run without a valid session")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to /auth/login, got %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/auth/login" {
+		t.Fatalf("expected redirect to /auth/login, got %q", loc)
+	}
+}
+
+// TestRequireAuth_AllowsValidSession confirms a valid session cookie
+// both passes the request through and attaches its email to the
+// context, the way handlers recover it with sessionEmail.
+func TestRequireAuth_AllowsValidSession(t *testing.T) {
+	sessions := NewSessionStore([]byte("test-session-key"), true)
+	s := &Server{sessions: sessions}
+
+	var gotEmail string
+	handler := s.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEmail = sessionEmail(r)
+	}))
+
+	issued := httptest.NewRecorder()
+	sessions.Issue(issued, "approver@example.com")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/approvals/pending", nil)
+	for _, c := range issued.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if gotEmail != "approver@example.com" {
+		t.Fatalf("expected session email to reach the handler, got %q", gotEmail)
+	}
+}
+
+// TestSessionStore_CookieRoundTripsOverPlainHTTP confirms a cookie issued
+// with secure=false actually comes back on the next request over plain
+// HTTP - the dev provider's workflow - using a real HTTP client and
+// cookie jar rather than hand-copying cookies in-process, which would
+// miss the Secure attribute being honored by the client.
+func TestSessionStore_CookieRoundTripsOverPlainHTTP(t *testing.T) {
+	sessions := NewSessionStore([]byte("test-session-key"), false)
+
+	var gotEmail string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/issue", func(w http.ResponseWriter, r *http.Request) {
+		sessions.Issue(w, "dev@example.com")
+	})
+	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
+		gotEmail, _ = sessions.Email(r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New: %v", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	if _, err := client.Get(srv.URL + "/issue"); err != nil {
+		t.Fatalf("GET /issue: %v", err)
+	}
+	if _, err := client.Get(srv.URL + "/check"); err != nil {
+		t.Fatalf("GET /check: %v", err)
+	}
+
+	if gotEmail != "dev@example.com" {
+		t.Fatalf("expected the session cookie to round-trip over plain HTTP, got email %q", gotEmail)
+	}
+}
+
+// TestSessionStore_SecureCookieDroppedOverPlainHTTP documents why
+// secure must be false for the dev provider: a cookie issued with
+// secure=true never comes back from a standards-compliant client over
+// plain HTTP, which is exactly the loop this fix resolves for secure=false.
+func TestSessionStore_SecureCookieDroppedOverPlainHTTP(t *testing.T) {
+	sessions := NewSessionStore([]byte("test-session-key"), true)
+
+	var gotEmail string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/issue", func(w http.ResponseWriter, r *http.Request) {
+		sessions.Issue(w, "dev@example.com")
+	})
+	mux.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
+		gotEmail, _ = sessions.Email(r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatalf("cookiejar.New: %v", err)
+	}
+	client := &http.Client{Jar: jar}
+
+	if _, err := client.Get(srv.URL + "/issue"); err != nil {
+		t.Fatalf("GET /issue: %v", err)
+	}
+	if _, err := client.Get(srv.URL + "/check"); err != nil {
+		t.Fatalf("GET /check: %v", err)
+	}
+
+	if gotEmail != "" {
+		t.Fatalf("expected a Secure cookie not to round-trip over plain HTTP, got email %q", gotEmail)
+	}
+}
@@ -0,0 +1,479 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the full set of operator-tunable settings for the server.
+// Durations are stored as strings (e.g. "5m") so they round-trip cleanly
+// through both YAML and JSON.
+type Config struct {
+	NetSuite     NetSuiteConfig    `yaml:"netsuite" json:"netsuite"`
+	Approval     ApprovalConfig    `yaml:"approval" json:"approval"`
+	Auth         AuthConfig        `yaml:"auth" json:"auth"`
+	Session      SessionConfig     `yaml:"session" json:"session"`
+	Retry        RetryConfig       `yaml:"retry" json:"retry"`
+	Health       HealthConfig      `yaml:"health" json:"health"`
+	Admin        AdminConfig       `yaml:"admin" json:"admin"`
+	CacheTTL     map[string]string `yaml:"cacheTtl" json:"cacheTtl"`
+	FeatureFlags map[string]bool   `yaml:"featureFlags" json:"featureFlags"`
+}
+
+// AdminConfig lists who may read and change operator settings through
+// /admin/config. Signing in via OIDC is not enough on its own - that
+// only proves who someone is, not that they're allowed to see secrets
+// or rewrite NetSuite/session credentials.
+type AdminConfig struct {
+	Emails []string `yaml:"emails" json:"emails"`
+}
+
+// isAdmin reports whether email is on the configured admin allow-list.
+func (c *Config) isAdmin(email string) bool {
+	if email == "" {
+		return false
+	}
+	for _, admin := range c.Admin.Emails {
+		if strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthConfig tunes the /readyz freshness window.
+type HealthConfig struct {
+	ReadyWindow string `yaml:"readyWindow" json:"readyWindow"`
+}
+
+// readyWindow is how recently NetSuite must have answered successfully
+// for /readyz to report ready, falling back to a sane default.
+func (c *Config) readyWindow() time.Duration {
+	if d, err := time.ParseDuration(c.Health.ReadyWindow); err == nil && d > 0 {
+		return d
+	}
+	return 2 * time.Minute
+}
+
+type NetSuiteConfig struct {
+	AccountID      string `yaml:"accountId" json:"accountId"`
+	Realm          string `yaml:"realm" json:"realm"`
+	ConsumerKey    string `yaml:"consumerKey" json:"consumerKey"`
+	ConsumerSecret string `yaml:"consumerSecret" json:"consumerSecret"`
+	TokenID        string `yaml:"tokenId" json:"tokenId"`
+	TokenSecret    string `yaml:"tokenSecret" json:"tokenSecret"`
+	RestletURL     string `yaml:"restletUrl" json:"restletUrl"`
+}
+
+type ApprovalConfig struct {
+	DBPath           string `yaml:"dbPath" json:"dbPath"`
+	HMACSecret       string `yaml:"hmacSecret" json:"hmacSecret"`
+	DefaultManagerID string `yaml:"defaultManagerId" json:"defaultManagerId"`
+	DefaultFinanceID string `yaml:"defaultFinanceId" json:"defaultFinanceId"`
+}
+
+type AuthConfig struct {
+	Provider     string `yaml:"provider" json:"provider"` // "oidc" or "dev"
+	Issuer       string `yaml:"issuer" json:"issuer"`
+	ClientID     string `yaml:"clientId" json:"clientId"`
+	ClientSecret string `yaml:"clientSecret" json:"clientSecret"`
+	RedirectURL  string `yaml:"redirectUrl" json:"redirectUrl"`
+	DevEmail     string `yaml:"devEmail" json:"devEmail"`
+}
+
+type SessionConfig struct {
+	HMACSecret string `yaml:"hmacSecret" json:"hmacSecret"`
+}
+
+type RetryConfig struct {
+	MaxAttempts int    `yaml:"maxAttempts" json:"maxAttempts"`
+	Deadline    string `yaml:"deadline" json:"deadline"`
+	BackoffBase string `yaml:"backoffBase" json:"backoffBase"`
+}
+
+// CallOptions turns the parsed retry policy into a CallOptions, falling
+// back to DefaultCallOptions for any field that doesn't parse.
+func (r RetryConfig) CallOptions() CallOptions {
+	opts := DefaultCallOptions()
+	if r.MaxAttempts > 0 {
+		opts.MaxAttempts = r.MaxAttempts
+	}
+	if d, err := time.ParseDuration(r.Deadline); err == nil && d > 0 {
+		opts.Deadline = d
+	}
+	if d, err := time.ParseDuration(r.BackoffBase); err == nil && d > 0 {
+		opts.BackoffBase = d
+	}
+	return opts
+}
+
+// cacheTTL looks up the configured TTL for a named cache (e.g.
+// "employees"), falling back to def if it's missing or doesn't parse.
+func (c *Config) cacheTTL(name string, def time.Duration) time.Duration {
+	raw, ok := c.CacheTTL[name]
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// ConfigConflictError is returned by DoLockedAction when the fingerprint
+// passed in no longer matches the live config - someone else's update
+// landed first.
+type ConfigConflictError struct {
+	Have string
+	Want string
+}
+
+func (e *ConfigConflictError) Error() string {
+	return fmt.Sprintf("config changed underneath this update (have fingerprint %s, expected %s)", e.Have, e.Want)
+}
+
+// ConfigHandler loads config.yaml, serves it behind an atomic pointer so
+// readers never see a torn update, and accepts fingerprint-guarded
+// mutations so two concurrent operators can't silently clobber each
+// other's changes.
+type ConfigHandler struct {
+	path     string
+	current  atomic.Pointer[Config]
+	onChange func(previous, next *Config)
+
+	// mu serializes DoLockedAction's check-modify-write-store sequence.
+	// Without it, two callers can both pass the fingerprint check against
+	// the same live config before either calls Store, and the second
+	// Store silently discards the first caller's update.
+	mu sync.Mutex
+}
+
+// OnChange registers fn to run after every successful Reload or
+// DoLockedAction, whether the change came from SIGHUP or the admin API,
+// so callers only have to wire up side effects like cache invalidation
+// once.
+func (h *ConfigHandler) OnChange(fn func(previous, next *Config)) {
+	h.onChange = fn
+}
+
+// NewConfigHandler loads path and returns a handler serving it.
+func NewConfigHandler(path string) (*ConfigHandler, error) {
+	h := &ConfigHandler{path: path}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Get returns the currently live config. Callers must not mutate it -
+// treat it as read-only and go through DoLockedAction to change it.
+func (h *ConfigHandler) Get() *Config {
+	return h.current.Load()
+}
+
+// Fingerprint returns the SHA-256 hex digest of the live config's
+// canonical JSON encoding.
+func (h *ConfigHandler) Fingerprint() string {
+	return fingerprintOf(h.current.Load())
+}
+
+func fingerprintOf(cfg *Config) string {
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// Reload re-reads the config file from disk and swaps it in, logging
+// what changed. It's what the SIGHUP handler calls to pick up edits made
+// directly to config.yaml.
+func (h *ConfigHandler) Reload() error {
+	raw, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return errConfigNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return fmt.Errorf("parse config: %w", err)
+	}
+
+	previous := h.current.Load()
+	h.current.Store(&cfg)
+	logConfigDiff(previous, &cfg)
+	if h.onChange != nil {
+		h.onChange(previous, &cfg)
+	}
+	return nil
+}
+
+// DoLockedAction applies fn to a copy of the live config, but only if
+// fingerprint still matches what's live - optimistic concurrency so a
+// stale read-modify-write can't silently overwrite someone else's
+// update. On success the new config is written to disk and swapped in.
+func (h *ConfigHandler) DoLockedAction(fingerprint string, fn func(*Config) error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	live := h.current.Load()
+	if have := fingerprintOf(live); have != fingerprint {
+		return &ConfigConflictError{Have: have, Want: fingerprint}
+	}
+
+	next := *live // shallow copy is enough: fn is expected to replace whole sub-structs, not mutate maps in place
+	if err := fn(&next); err != nil {
+		return err
+	}
+
+	raw, err := yaml.Marshal(&next)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := os.WriteFile(h.path, raw, 0o600); err != nil {
+		return fmt.Errorf("write config: %w", err)
+	}
+
+	h.current.Store(&next)
+	logConfigDiff(live, &next)
+	if h.onChange != nil {
+		h.onChange(live, &next)
+	}
+	return nil
+}
+
+// WatchSIGHUP reloads the config from disk every time the process
+// receives SIGHUP. Settings read through Get on each request (cache TTLs,
+// the readiness window, approval routing, admin emails) take effect
+// immediately. The NetSuite client, session store and approval manager
+// keep the credentials and retry policy they were built with at startup;
+// a reload does not change those.
+func (h *ConfigHandler) WatchSIGHUP() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if err := h.Reload(); err != nil {
+				logConfigReloadError(err)
+			}
+		}
+	}()
+}
+
+func logConfigReloadError(err error) {
+	fmt.Fprintf(os.Stderr, "config reload failed: %v\n", err)
+}
+
+// logConfigDiff logs a one-line summary of what changed between two
+// config versions, without ever printing secrets.
+func logConfigDiff(previous, next *Config) {
+	if previous == nil {
+		fmt.Fprintf(os.Stderr, "config loaded: fingerprint=%s\n", fingerprintOf(next))
+		return
+	}
+	if fingerprintOf(previous) == fingerprintOf(next) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "config changed: %s -> %s\n", fingerprintOf(previous), fingerprintOf(next))
+}
+
+var errConfigNotFound = errors.New("config file not found; set CONFIG_PATH or create config.yaml")
+
+// handleAdminConfig serves GET /admin/config (the live config plus its
+// fingerprint in the ETag header) and PATCH /admin/config (a partial
+// update of the named top-level sections, guarded by an If-Match
+// fingerprint for optimistic concurrency). Both methods are restricted
+// to the operators on config.admin.emails - signing in is not the same
+// as being authorized to read or change NetSuite/session/OIDC secrets.
+func (s *Server) handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.config.Get().isAdmin(sessionEmail(r)) {
+		writeError(w, http.StatusForbidden, "admin access required")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("ETag", s.config.Fingerprint())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.config.Get())
+
+	case http.MethodPatch:
+		ifMatch := r.Header.Get("If-Match")
+		if ifMatch == "" {
+			writeError(w, http.StatusBadRequest, "If-Match header with the config fingerprint is required")
+			return
+		}
+
+		var sections map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&sections); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid config body")
+			return
+		}
+
+		err := s.config.DoLockedAction(ifMatch, func(cfg *Config) error {
+			return mergeConfigSections(cfg, sections)
+		})
+		if err != nil {
+			var conflict *ConfigConflictError
+			if errors.As(err, &conflict) {
+				writeError(w, http.StatusConflict, conflict.Error())
+				return
+			}
+			var input *configInputError
+			if errors.As(err, &input) {
+				writeError(w, http.StatusBadRequest, input.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("ETag", s.config.Fingerprint())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.config.Get())
+
+	default:
+		w.Header().Set("Allow", "GET, PATCH")
+		writeError(w, http.StatusMethodNotAllowed, "unsupported method")
+	}
+}
+
+// mergeConfigSections applies only the top-level sections present in
+// raw onto cfg, leaving every section a caller didn't send - including
+// any secret it wasn't trying to touch - exactly as it was. This keeps
+// PATCH /admin/config a true partial update rather than a full replace
+// that silently blanks whatever the caller forgot to echo back.
+//
+// cfg is DoLockedAction's shallow copy of the live config, so its map
+// and slice fields still share backing storage with the config every
+// other goroutine is reading. json.Unmarshal reuses (rather than
+// replaces) existing maps and can reuse a slice's backing array too, so
+// decoding straight into cfg's fields would mutate the live config out
+// from under concurrent readers. Decoding into clones first and only
+// assigning them onto cfg once decoding succeeds keeps the live config
+// untouched until DoLockedAction atomically swaps it in.
+func mergeConfigSections(cfg *Config, raw map[string]json.RawMessage) error {
+	admin := AdminConfig{Emails: append([]string(nil), cfg.Admin.Emails...)}
+	cacheTTL := cloneStringMap(cfg.CacheTTL)
+	featureFlags := cloneBoolMap(cfg.FeatureFlags)
+
+	sections := map[string]any{
+		"netsuite":     &cfg.NetSuite,
+		"approval":     &cfg.Approval,
+		"auth":         &cfg.Auth,
+		"session":      &cfg.Session,
+		"retry":        &cfg.Retry,
+		"health":       &cfg.Health,
+		"admin":        &admin,
+		"cacheTtl":     &cacheTTL,
+		"featureFlags": &featureFlags,
+	}
+
+	for key, value := range raw {
+		target, ok := sections[key]
+		if !ok {
+			return &configInputError{fmt.Sprintf("unknown config section %q", key)}
+		}
+		if bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+			// A literal JSON null means "section present but no value" -
+			// leave it untouched rather than letting json.Unmarshal wipe
+			// a map/slice field to nil, same as an absent key would.
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			return &configInputError{fmt.Sprintf("invalid %s section: %v", key, err)}
+		}
+	}
+
+	cfg.Admin = admin
+	cfg.CacheTTL = cacheTTL
+	cfg.FeatureFlags = featureFlags
+
+	return validateConfigSecrets(cfg)
+}
+
+// configInputError marks a PATCH /admin/config failure as the caller's
+// fault - an unknown section, invalid JSON, or a would-be-blank secret
+// - as opposed to an internal failure like a failed disk write, so the
+// handler can tell the two apart and answer 400 vs 500.
+type configInputError struct {
+	msg string
+}
+
+func (e *configInputError) Error() string { return e.msg }
+
+func cloneStringMap(m map[string]string) map[string]string {
+	clone := make(map[string]string, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func cloneBoolMap(m map[string]bool) map[string]bool {
+	clone := make(map[string]bool, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+// validateConfigSecrets rejects a config update that would leave a
+// required secret blank, whether that's a caller forgetting a field or
+// a section patch dropping a sibling it didn't mean to touch. It also
+// refuses to let admin.emails go empty - reaching this point already
+// required the caller to be on that list, and an empty list would lock
+// every operator out of /admin/config with no way back in short of
+// editing config.yaml on disk.
+func validateConfigSecrets(cfg *Config) error {
+	if cfg.NetSuite.RestletURL != "" && (cfg.NetSuite.ConsumerSecret == "" || cfg.NetSuite.TokenSecret == "") {
+		return &configInputError{"netsuite.consumerSecret and netsuite.tokenSecret must not be blank"}
+	}
+	if cfg.Session.HMACSecret == "" {
+		return &configInputError{"session.hmacSecret must not be blank"}
+	}
+	if cfg.Approval.HMACSecret == "" {
+		return &configInputError{"approval.hmacSecret must not be blank"}
+	}
+	if cfg.Auth.Provider != "dev" && cfg.Auth.ClientSecret == "" {
+		return &configInputError{"auth.clientSecret must not be blank"}
+	}
+	if len(cfg.Admin.Emails) == 0 {
+		return &configInputError{"admin.emails must not be blank"}
+	}
+	return nil
+}
+
+// invalidateChangedCaches drops any in-memory cache whose TTL changed so
+// the next request picks up the new value's freshness window rather than
+// serving an entry primed under the old policy.
+func (s *Server) invalidateChangedCaches(before, after *Config) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	if before.CacheTTL["employees"] != after.CacheTTL["employees"] {
+		s.employeeCacheTime = time.Time{}
+	}
+	if before.CacheTTL["locations"] != after.CacheTTL["locations"] {
+		s.locationCacheTime = time.Time{}
+	}
+}
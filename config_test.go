@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, path string) {
+	t.Helper()
+	const yaml = `
+session:
+  hmacSecret: test-session-secret
+approval:
+  hmacSecret: test-approval-secret
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatalf("write test config: %v", err)
+	}
+}
+
+// TestDoLockedAction_RejectsStaleFingerprint confirms a caller holding a
+// fingerprint that's since moved gets a ConfigConflictError instead of
+// silently clobbering whatever update landed first.
+func TestDoLockedAction_RejectsStaleFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path)
+
+	h, err := NewConfigHandler(path)
+	if err != nil {
+		t.Fatalf("NewConfigHandler: %v", err)
+	}
+	staleFingerprint := h.Fingerprint()
+
+	if err := h.DoLockedAction(staleFingerprint, func(cfg *Config) error {
+		cfg.Approval.DefaultManagerID = "manager-1"
+		return nil
+	}); err != nil {
+		t.Fatalf("first DoLockedAction: %v", err)
+	}
+
+	err = h.DoLockedAction(staleFingerprint, func(cfg *Config) error {
+		cfg.Approval.DefaultManagerID = "manager-2"
+		return nil
+	})
+
+	var conflict *ConfigConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected a ConfigConflictError, got %v", err)
+	}
+	if got := h.Get().Approval.DefaultManagerID; got != "manager-1" {
+		t.Fatalf("stale update should not have applied, got %q", got)
+	}
+}
+
+// TestDoLockedAction_AppliesOnMatchingFingerprint confirms the happy
+// path: a caller holding the live fingerprint can apply its change.
+func TestDoLockedAction_AppliesOnMatchingFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path)
+
+	h, err := NewConfigHandler(path)
+	if err != nil {
+		t.Fatalf("NewConfigHandler: %v", err)
+	}
+
+	err = h.DoLockedAction(h.Fingerprint(), func(cfg *Config) error {
+		cfg.Approval.DefaultManagerID = "manager-1"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DoLockedAction: %v", err)
+	}
+	if got := h.Get().Approval.DefaultManagerID; got != "manager-1" {
+		t.Fatalf("expected update to apply, got %q", got)
+	}
+}
+
+// TestDoLockedAction_SerializesConcurrentConflictingUpdates confirms that
+// when several callers race with the same stale fingerprint, exactly one
+// wins and the rest see a ConfigConflictError - not a check-then-write
+// race where more than one appears to succeed.
+func TestDoLockedAction_SerializesConcurrentConflictingUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path)
+
+	h, err := NewConfigHandler(path)
+	if err != nil {
+		t.Fatalf("NewConfigHandler: %v", err)
+	}
+	staleFingerprint := h.Fingerprint()
+
+	const callers = 8
+	results := make(chan error, callers)
+	var start sync.WaitGroup
+	start.Add(1)
+	for i := 0; i < callers; i++ {
+		go func(i int) {
+			start.Wait()
+			results <- h.DoLockedAction(staleFingerprint, func(cfg *Config) error {
+				cfg.Approval.DefaultManagerID = "manager-1"
+				return nil
+			})
+		}(i)
+	}
+	start.Done()
+
+	succeeded := 0
+	for i := 0; i < callers; i++ {
+		if err := <-results; err == nil {
+			succeeded++
+		} else if !errors.As(err, new(*ConfigConflictError)) {
+			t.Fatalf("expected nil or ConfigConflictError, got %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly 1 of %d racing updates to succeed, got %d", callers, succeeded)
+	}
+}
+
+// TestMergeConfigSections_LeavesNullSectionsUntouched confirms a caller
+// sending an explicit JSON null for a map-typed section doesn't wipe it
+// - a null should behave like an absent key, not an empty map.
+func TestMergeConfigSections_LeavesNullSectionsUntouched(t *testing.T) {
+	cfg := &Config{
+		Auth:     AuthConfig{Provider: "dev"},
+		Session:  SessionConfig{HMACSecret: "s"},
+		Approval: ApprovalConfig{HMACSecret: "a"},
+		Admin:    AdminConfig{Emails: []string{"admin@example.com"}},
+		CacheTTL: map[string]string{"employees": "5m"},
+	}
+
+	raw := map[string]json.RawMessage{"cacheTtl": json.RawMessage("null")}
+	if err := mergeConfigSections(cfg, raw); err != nil {
+		t.Fatalf("mergeConfigSections: %v", err)
+	}
+	if cfg.CacheTTL["employees"] != "5m" {
+		t.Fatalf("expected cacheTtl to be left untouched, got %v", cfg.CacheTTL)
+	}
+}
+
+// TestMergeConfigSections_RejectsBlankAdminEmails confirms a PATCH can't
+// wipe admin.emails to empty, which would lock every operator out.
+func TestMergeConfigSections_RejectsBlankAdminEmails(t *testing.T) {
+	cfg := &Config{
+		Auth:     AuthConfig{Provider: "dev"},
+		Session:  SessionConfig{HMACSecret: "s"},
+		Approval: ApprovalConfig{HMACSecret: "a"},
+		Admin:    AdminConfig{Emails: []string{"admin@example.com"}},
+	}
+
+	raw := map[string]json.RawMessage{"admin": json.RawMessage(`{"emails":[]}`)}
+	err := mergeConfigSections(cfg, raw)
+
+	var input *configInputError
+	if !errors.As(err, &input) {
+		t.Fatalf("expected a configInputError, got %v", err)
+	}
+}
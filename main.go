@@ -31,7 +31,12 @@ var embeddedFiles embed.FS
 type Server struct {
 	templates map[string]*template.Template
 	netsuite  *NetSuiteClient
-	
+	approvals *ApprovalManager
+	auth      AuthProvider
+	sessions  *SessionStore
+	config    *ConfigHandler
+	metrics   *Metrics
+
 	// Cache for slow-changing data
 	employeeCache     []byte
 	employeeCacheTime time.Time
@@ -96,7 +101,14 @@ type RequisitionLine struct {
 }
 
 func main() {
-	_ = loadDotEnv(".env")
+	configPath := os.Getenv("CONFIG_PATH")
+	if configPath == "" {
+		configPath = "config.yaml"
+	}
+	config, err := NewConfigHandler(configPath)
+	if err != nil {
+		log.Fatalf("config not loaded: %v", err)
+	}
 
 	templates := make(map[string]*template.Template)
 
@@ -105,15 +117,44 @@ func main() {
 	templates["requests"] = template.Must(template.ParseFS(embeddedFiles, "templates/base.html", "templates/requests.html"))
 	templates["requests_list"] = template.Must(template.ParseFS(embeddedFiles, "templates/partials/requests_list.html"))
 
-	client, err := NewNetSuiteClientFromEnv()
+	metrics := NewMetrics()
+
+	client, err := NewNetSuiteClientFromConfig(config.Get(), metrics)
 	if err != nil {
 		log.Printf("NetSuite client not configured: %v", err)
 	}
 
+	approvals, err := newApprovalManager(config.Get().Approval, client)
+	if err != nil {
+		log.Printf("approval workflow not configured: %v", err)
+	} else {
+		approvals.StartNightlyVerifier(24 * time.Hour)
+	}
+
+	authCtx, cancelAuth := context.WithTimeout(context.Background(), 10*time.Second)
+	authProvider, err := NewAuthProviderFromConfig(authCtx, config.Get().Auth)
+	cancelAuth()
+	if err != nil {
+		log.Fatalf("auth provider not configured: %v", err)
+	}
+
+	if config.Get().Session.HMACSecret == "" {
+		log.Fatal("session.hmacSecret is not set in config")
+	}
+
 	server := &Server{
 		templates: templates,
 		netsuite:  client,
+		approvals: approvals,
+		auth:      authProvider,
+		sessions:  NewSessionStore([]byte(config.Get().Session.HMACSecret), config.Get().Auth.Provider != "dev"),
+		config:    config,
+		metrics:   metrics,
 	}
+	server.StartReadinessPinger(30 * time.Second)
+
+	config.OnChange(server.invalidateChangedCaches)
+	config.WatchSIGHUP()
 
 	mux := http.NewServeMux()
 
@@ -123,16 +164,40 @@ func main() {
 	}
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
 
-	mux.HandleFunc("/", server.handleNewRequisition)
-	mux.HandleFunc("/requests", server.handleRequestsPage)
+	mux.HandleFunc("/auth/login", server.handleLogin)
+	mux.HandleFunc("/auth/callback", server.handleAuthCallback)
+	mux.HandleFunc("/auth/logout", server.handleLogout)
+
+	mux.Handle("/", server.RequireAuth(http.HandlerFunc(server.handleNewRequisition)))
+	mux.Handle("/requests", server.RequireAuth(http.HandlerFunc(server.handleRequestsPage)))
+
+	mux.Handle("/api/employees", server.RequireAuth(http.HandlerFunc(server.handleEmployees)))
+	mux.Handle("/api/locations", server.RequireAuth(http.HandlerFunc(server.handleLocations)))
+	mux.Handle("/api/items", server.RequireAuth(http.HandlerFunc(server.handleItems)))
+	mux.Handle("/api/item-vendors", server.RequireAuth(http.HandlerFunc(server.handleItemVendors)))
+	mux.Handle("/api/vendors", server.RequireAuth(http.HandlerFunc(server.handleVendors)))
+	mux.Handle("/api/requisitions", server.RequireAuth(http.HandlerFunc(server.handleCreateRequisition)))
+	mux.Handle("/api/requests", server.RequireAuth(http.HandlerFunc(server.handleRequests)))
+
+	mux.Handle("/api/approvals/pending", server.RequireAuth(http.HandlerFunc(server.handlePendingApprovals)))
+	mux.Handle("/api/approvals/", server.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/approve"):
+			server.handleApproveRequisition(w, r)
+		case strings.HasSuffix(r.URL.Path, "/reject"):
+			server.handleRejectRequisition(w, r)
+		case strings.HasSuffix(r.URL.Path, "/verify"):
+			server.handleVerifyChain(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})))
+
+	mux.Handle("/admin/config", server.RequireAuth(http.HandlerFunc(server.handleAdminConfig)))
 
-	mux.HandleFunc("/api/employees", server.handleEmployees)
-	mux.HandleFunc("/api/locations", server.handleLocations)
-	mux.HandleFunc("/api/items", server.handleItems)
-	mux.HandleFunc("/api/item-vendors", server.handleItemVendors)
-	mux.HandleFunc("/api/vendors", server.handleVendors)
-	mux.HandleFunc("/api/requisitions", server.handleCreateRequisition)
-	mux.HandleFunc("/api/requests", server.handleRequests)
+	mux.HandleFunc("/healthz", server.handleHealthz)
+	mux.HandleFunc("/readyz", server.handleReadyz)
+	mux.Handle("/metrics", server.metrics.Handler())
 
 	addr := ":8080"
 	if port := os.Getenv("PORT"); port != "" {
@@ -145,34 +210,6 @@ func main() {
 	}
 }
 
-func loadDotEnv(filename string) error {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return err
-	}
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-		value = strings.Trim(value, `"'`)
-		if key == "" {
-			continue
-		}
-		if _, exists := os.LookupEnv(key); !exists {
-			_ = os.Setenv(key, value)
-		}
-	}
-	return nil
-}
-
 func (s *Server) handleNewRequisition(w http.ResponseWriter, r *http.Request) {
 	data := TemplateData{
 		Title:  "New Requisition",
@@ -197,18 +234,21 @@ func (s *Server) handleEmployees(w http.ResponseWriter, r *http.Request) {
 
 	// Check cache first
 	s.cacheMu.RLock()
-	if s.employeeCache != nil && time.Since(s.employeeCacheTime) < cacheDuration {
+	if s.employeeCache != nil && time.Since(s.employeeCacheTime) < s.config.Get().cacheTTL("employees", cacheDuration) {
 		data := s.employeeCache
+		age := time.Since(s.employeeCacheTime)
 		s.cacheMu.RUnlock()
+		s.metrics.recordCacheHit("employees", age)
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(data)
 		return
 	}
 	s.cacheMu.RUnlock()
+	s.metrics.recordCacheMiss("employees")
 
-	payload, err := s.netsuite.Call(r.Context(), http.MethodGet, map[string]string{
+	payload, err := s.netsuite.CallWithOptions(r.Context(), http.MethodGet, map[string]string{
 		"action": "employees",
-	}, nil)
+	}, nil, CacheRefreshCallOptions())
 	if err != nil {
 		writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -219,6 +259,7 @@ func (s *Server) handleEmployees(w http.ResponseWriter, r *http.Request) {
 	s.employeeCache = payload
 	s.employeeCacheTime = time.Now()
 	s.cacheMu.Unlock()
+	s.metrics.recordCacheRefresh("employees")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(payload)
@@ -232,18 +273,21 @@ func (s *Server) handleLocations(w http.ResponseWriter, r *http.Request) {
 
 	// Check cache first
 	s.cacheMu.RLock()
-	if s.locationCache != nil && time.Since(s.locationCacheTime) < cacheDuration {
+	if s.locationCache != nil && time.Since(s.locationCacheTime) < s.config.Get().cacheTTL("locations", cacheDuration) {
 		data := s.locationCache
+		age := time.Since(s.locationCacheTime)
 		s.cacheMu.RUnlock()
+		s.metrics.recordCacheHit("locations", age)
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(data)
 		return
 	}
 	s.cacheMu.RUnlock()
+	s.metrics.recordCacheMiss("locations")
 
-	payload, err := s.netsuite.Call(r.Context(), http.MethodGet, map[string]string{
+	payload, err := s.netsuite.CallWithOptions(r.Context(), http.MethodGet, map[string]string{
 		"action": "locations",
-	}, nil)
+	}, nil, CacheRefreshCallOptions())
 	if err != nil {
 		writeError(w, http.StatusBadGateway, err.Error())
 		return
@@ -254,6 +298,7 @@ func (s *Server) handleLocations(w http.ResponseWriter, r *http.Request) {
 	s.locationCache = payload
 	s.locationCacheTime = time.Now()
 	s.cacheMu.Unlock()
+	s.metrics.recordCacheRefresh("locations")
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(payload)
@@ -337,8 +382,8 @@ func (s *Server) handleVendors(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleCreateRequisition(w http.ResponseWriter, r *http.Request) {
-	if s.netsuite == nil {
-		writeError(w, http.StatusServiceUnavailable, "NetSuite restlet is not configured yet.")
+	if s.approvals == nil {
+		writeError(w, http.StatusServiceUnavailable, "approval workflow is not configured yet.")
 		return
 	}
 
@@ -347,6 +392,12 @@ func (s *Server) handleCreateRequisition(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	requestorID, err := s.resolveEmployeeID(r.Context(), sessionEmail(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var items []RequisitionItem
 	itemsJSON := r.FormValue("itemsJson")
 	if itemsJSON != "" {
@@ -358,17 +409,13 @@ func (s *Server) handleCreateRequisition(w http.ResponseWriter, r *http.Request)
 
 	req := CreateRequisitionRequest{
 		Action:      "createRequisition",
-		RequestorID: strings.TrimSpace(r.FormValue("requestorId")),
+		RequestorID: requestorID,
 		Subsidiary:  strings.TrimSpace(r.FormValue("subsidiary")),
 		Location:    strings.TrimSpace(r.FormValue("location")),
 		Memo:        strings.TrimSpace(r.FormValue("notes")),
 		Items:       items,
 	}
 
-	if req.RequestorID == "" {
-		writeError(w, http.StatusBadRequest, "select your name first")
-		return
-	}
 	if req.Subsidiary == "" {
 		writeError(w, http.StatusBadRequest, "subsidiary is required")
 		return
@@ -378,36 +425,44 @@ func (s *Server) handleCreateRequisition(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	payload, err := s.netsuite.Call(r.Context(), http.MethodPost, nil, req)
+	approvers := s.approvalRoutingFor(req)
+	draft, err := s.approvals.CreateDraft(req, req.RequestorID, approvers)
 	if err != nil {
-		writeError(w, http.StatusBadGateway, err.Error())
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	s.metrics.recordRequisitionCreated()
 
-	var response struct {
-		ID     string `json:"id"`
-		TranID string `json:"tranId"`
-	}
-	_ = json.Unmarshal(payload, &response)
-
-	message := "Requisition submitted."
-	if response.TranID != "" {
-		message = fmt.Sprintf("Requisition %s submitted.", response.TranID)
-	}
+	message := fmt.Sprintf("Requisition %s submitted for approval.", draft.ID)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	io.WriteString(w, fmt.Sprintf(`<div class="status status-success">%s</div>`, template.HTMLEscapeString(message)))
 }
 
+// approvalRoutingFor assigns the approver gating each stage a requisition
+// passes through before posting. Approve/Reject check the caller against
+// Approvers[draft.Stage], so every stage a draft can be approved out of
+// needs an entry here - a fresh draft starts at StageManagerReview (see
+// CreateDraft), so there are exactly two: manager, then finance. Routing
+// is flat for now; a future rule set can key this off subsidiary,
+// location or total spend without changing callers.
+func (s *Server) approvalRoutingFor(req CreateRequisitionRequest) map[Stage]string {
+	approval := s.config.Get().Approval
+	return map[Stage]string{
+		StageManagerReview: approval.DefaultManagerID,
+		StageFinanceReview: approval.DefaultFinanceID,
+	}
+}
+
 func (s *Server) handleRequests(w http.ResponseWriter, r *http.Request) {
 	if s.netsuite == nil {
 		writeError(w, http.StatusServiceUnavailable, "NetSuite restlet is not configured yet.")
 		return
 	}
 
-	employeeID := strings.TrimSpace(r.URL.Query().Get("employeeId"))
-	if employeeID == "" {
-		writeError(w, http.StatusBadRequest, "employeeId is required")
+	employeeID, err := s.resolveEmployeeID(r.Context(), sessionEmail(r))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -466,7 +521,6 @@ func statusToClass(status string) string {
 	}
 }
 
-
 func writeError(w http.ResponseWriter, status int, message string) {
 	w.WriteHeader(status)
 	io.WriteString(w, fmt.Sprintf(`<div class="status status-error">%s</div>`, template.HTMLEscapeString(message)))
@@ -481,47 +535,75 @@ type NetSuiteClient struct {
 	tokenSecret    string
 	restletURL     *url.URL
 	httpClient     *http.Client
+	promMetrics    *Metrics
+	defaultOptions CallOptions
 }
 
-func NewNetSuiteClientFromEnv() (*NetSuiteClient, error) {
-	accountID := os.Getenv("NETSUITE_ACCOUNT_ID")
-	realm := os.Getenv("NETSUITE_REALM")
-	consumerKey := os.Getenv("NETSUITE_CONSUMER_KEY")
-	consumerSecret := os.Getenv("NETSUITE_CONSUMER_SECRET")
-	tokenID := os.Getenv("NETSUITE_TOKEN_ID")
-	tokenSecret := os.Getenv("NETSUITE_TOKEN_SECRET")
-	restletURL := os.Getenv("NETSUITE_RESTLET_URL")
+// NewNetSuiteClientFromConfig builds a NetSuiteClient from the live
+// config's netsuite and retry sections, recording call outcomes against
+// promMetrics.
+func NewNetSuiteClientFromConfig(cfg *Config, promMetrics *Metrics) (*NetSuiteClient, error) {
+	ns := cfg.NetSuite
 
-	if restletURL == "" {
-		return nil, errors.New("NETSUITE_RESTLET_URL is not set")
+	if ns.RestletURL == "" {
+		return nil, errors.New("netsuite.restletUrl is not set")
 	}
 
-	parsedURL, err := url.Parse(restletURL)
+	parsedURL, err := url.Parse(ns.RestletURL)
 	if err != nil {
-		return nil, fmt.Errorf("invalid NETSUITE_RESTLET_URL: %w", err)
+		return nil, fmt.Errorf("invalid netsuite.restletUrl: %w", err)
 	}
 
-	if accountID == "" || consumerKey == "" || consumerSecret == "" || tokenID == "" || tokenSecret == "" {
-		return nil, errors.New("NetSuite OAuth credentials are missing")
+	if ns.AccountID == "" || ns.ConsumerKey == "" || ns.ConsumerSecret == "" || ns.TokenID == "" || ns.TokenSecret == "" {
+		return nil, errors.New("NetSuite OAuth credentials are missing from config")
 	}
 
+	realm := ns.Realm
 	if realm == "" {
-		realm = accountID
+		realm = ns.AccountID
 	}
 
 	return &NetSuiteClient{
-		accountID:      accountID,
+		accountID:      ns.AccountID,
 		realm:          realm,
-		consumerKey:    consumerKey,
-		consumerSecret: consumerSecret,
-		tokenID:        tokenID,
-		tokenSecret:    tokenSecret,
+		consumerKey:    ns.ConsumerKey,
+		consumerSecret: ns.ConsumerSecret,
+		tokenID:        ns.TokenID,
+		tokenSecret:    ns.TokenSecret,
 		restletURL:     parsedURL,
 		httpClient:     &http.Client{Timeout: 20 * time.Second},
+		promMetrics:    promMetrics,
+		defaultOptions: cfg.Retry.CallOptions(),
 	}, nil
 }
 
+// newApprovalManager wires up the approval subsystem from the config's
+// approval section (db path and the HMAC secret that signs the audit
+// hash chain).
+func newApprovalManager(cfg ApprovalConfig, netsuite *NetSuiteClient) (*ApprovalManager, error) {
+	if cfg.HMACSecret == "" {
+		return nil, errors.New("approval.hmacSecret is not set in config")
+	}
+
+	dbPath := cfg.DBPath
+	if dbPath == "" {
+		dbPath = "approvals.db"
+	}
+
+	return NewApprovalManager(dbPath, netsuite, []byte(cfg.HMACSecret))
+}
+
+// Call performs one NetSuite call using the client's retry policy (the
+// config's retry section over DefaultCallOptions). See
+// CallWithOptions for per-call deadlines and retry control.
 func (c *NetSuiteClient) Call(ctx context.Context, method string, params map[string]string, body interface{}) ([]byte, error) {
+	return c.CallWithOptions(ctx, method, params, body, c.defaultOptions)
+}
+
+// CallWithOptions performs one logical NetSuite call, retrying
+// individual attempts that time out or fail with a retryable status
+// while the overall attempt budget is bounded by ctx.
+func (c *NetSuiteClient) CallWithOptions(ctx context.Context, method string, params map[string]string, body interface{}, opts CallOptions) ([]byte, error) {
 	if c == nil {
 		return nil, errors.New("netsuite client is not configured")
 	}
@@ -542,32 +624,113 @@ func (c *NetSuiteClient) Call(ctx context.Context, method string, params map[str
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, urlCopy.String(), strings.NewReader(string(payload)))
+	action := params["action"]
+	start := time.Now()
+	record := func(status string) {
+		if c.promMetrics != nil {
+			c.promMetrics.observeNetSuiteCall(action, status, time.Since(start))
+		}
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt < opts.maxAttemptsOrDefault(); attempt++ {
+		if c.promMetrics != nil {
+			c.promMetrics.recordNetSuiteAttempt()
+			if attempt > 0 {
+				c.promMetrics.recordNetSuiteRetry()
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			record("cancelled")
+			return nil, err
+		}
+
+		responseBody, retryAfter, err := c.attempt(ctx, method, &urlCopy, payload, opts.deadlineOrDefault())
+		if err == nil {
+			log.Printf("NetSuite success: action=%s items=%d attempt=%d", action, len(responseBody), attempt+1)
+			record("success")
+			return responseBody, nil
+		}
+
+		lastErr = err
+		if !isRetryable(err, opts) {
+			record(netsuiteErrorStatus(err))
+			return nil, err
+		}
+		if attempt == opts.maxAttemptsOrDefault()-1 {
+			break
+		}
+
+		wait := backoffWithJitter(opts.backoffBaseOrDefault(), attempt)
+		if retryAfter > 0 && retryAfter > wait {
+			wait = retryAfter
+		}
+		log.Printf("NetSuite retry: action=%s attempt=%d wait=%s err=%v", action, attempt+1, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			record("cancelled")
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	record(netsuiteErrorStatus(lastErr))
+	return nil, lastErr
+}
+
+// attempt performs exactly one HTTP round trip, bounded by deadline on
+// top of whatever budget ctx already carries. It reports any Retry-After
+// duration the server sent so the retry loop can honor it.
+func (c *NetSuiteClient) attempt(ctx context.Context, method string, requestURL *url.URL, payload []byte, deadline time.Duration) ([]byte, time.Duration, error) {
+	dt := newDeadlineTimer(deadline)
+	defer dt.stop()
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-dt.C():
+			cancel()
+		case <-attemptCtx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(attemptCtx, method, requestURL.String(), strings.NewReader(string(payload)))
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", c.oauthHeader(method, &urlCopy, payload))
+	req.Header.Set("Authorization", c.oauthHeader(method, requestURL, payload))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		if dt.expired() {
+			if c.promMetrics != nil {
+				c.promMetrics.recordNetSuiteTimeout()
+			}
+			return nil, 0, fmt.Errorf("netsuite call timed out after %s: %w", deadline, err)
+		}
+		return nil, 0, err
 	}
 	defer resp.Body.Close()
 
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if resp.StatusCode >= 400 {
-		log.Printf("NetSuite error: status=%d url=%s response=%s", resp.StatusCode, urlCopy.String(), strings.TrimSpace(string(responseBody)))
-		return nil, fmt.Errorf("NetSuite error (%d): %s", resp.StatusCode, strings.TrimSpace(string(responseBody)))
+		log.Printf("NetSuite error: status=%d url=%s response=%s", resp.StatusCode, requestURL.String(), strings.TrimSpace(string(responseBody)))
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &netsuiteStatusError{status: resp.StatusCode, body: strings.TrimSpace(string(responseBody))}
 	}
 
-	log.Printf("NetSuite success: action=%s items=%d", params["action"], len(responseBody))
-	return responseBody, nil
+	return responseBody, 0, nil
 }
 
 func (c *NetSuiteClient) oauthHeader(method string, requestURL *url.URL, body []byte) string {
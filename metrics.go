@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds every Prometheus collector the server exports, plus the
+// small bit of state /readyz needs to decide whether NetSuite still
+// looks reachable.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	netsuiteCallsTotal        *prometheus.CounterVec
+	netsuiteCallDuration      *prometheus.HistogramVec
+	netsuiteCallAttemptsTotal prometheus.Counter
+	netsuiteCallRetriesTotal  prometheus.Counter
+	netsuiteCallTimeoutsTotal prometheus.Counter
+	cacheHitsTotal            *prometheus.CounterVec
+	cacheMissesTotal          *prometheus.CounterVec
+	requisitionsCreatedTotal  prometheus.Counter
+	cacheAgeSeconds           *prometheus.GaugeVec
+
+	mu             sync.RWMutex
+	lastNetSuiteOK time.Time
+}
+
+// NewMetrics builds and registers every collector against a fresh
+// registry, so tests (or a future second server instance) don't collide
+// on the global default registry.
+func NewMetrics() *Metrics {
+	registry := prometheus.NewRegistry()
+
+	m := &Metrics{
+		registry: registry,
+		netsuiteCallsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "netsuite_calls_total",
+			Help: "NetSuite restlet calls, by action and outcome status.",
+		}, []string{"action", "status"}),
+		netsuiteCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "netsuite_call_duration_seconds",
+			Help:    "NetSuite restlet call latency, by action.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"action"}),
+		netsuiteCallAttemptsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "netsuite_call_attempts_total",
+			Help: "HTTP attempts made across all NetSuite calls, including retries.",
+		}),
+		netsuiteCallRetriesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "netsuite_call_retries_total",
+			Help: "Attempts that were retries of an earlier failed attempt.",
+		}),
+		netsuiteCallTimeoutsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "netsuite_call_timeouts_total",
+			Help: "Attempts that failed because the per-attempt deadline expired.",
+		}),
+		cacheHitsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "In-memory cache hits, by cache name.",
+		}, []string{"cache"}),
+		cacheMissesTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "In-memory cache misses, by cache name.",
+		}, []string{"cache"}),
+		requisitionsCreatedTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "requisitions_created_total",
+			Help: "Requisitions submitted into the approval workflow.",
+		}),
+		cacheAgeSeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "cache_age_seconds",
+			Help: "Age of the most recent refresh of each in-memory cache.",
+		}, []string{"cache"}),
+	}
+
+	registry.MustRegister(
+		m.netsuiteCallsTotal,
+		m.netsuiteCallDuration,
+		m.netsuiteCallAttemptsTotal,
+		m.netsuiteCallRetriesTotal,
+		m.netsuiteCallTimeoutsTotal,
+		m.cacheHitsTotal,
+		m.cacheMissesTotal,
+		m.requisitionsCreatedTotal,
+		m.cacheAgeSeconds,
+	)
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) observeNetSuiteCall(action, status string, duration time.Duration) {
+	m.netsuiteCallsTotal.WithLabelValues(action, status).Inc()
+	m.netsuiteCallDuration.WithLabelValues(action).Observe(duration.Seconds())
+	if status == "success" {
+		m.markNetSuiteOK()
+	}
+}
+
+func (m *Metrics) markNetSuiteOK() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lastNetSuiteOK = time.Now()
+}
+
+func (m *Metrics) lastNetSuiteSuccess() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastNetSuiteOK
+}
+
+func (m *Metrics) recordCacheHit(cache string, age time.Duration) {
+	m.cacheHitsTotal.WithLabelValues(cache).Inc()
+	m.cacheAgeSeconds.WithLabelValues(cache).Set(age.Seconds())
+}
+
+func (m *Metrics) recordCacheMiss(cache string) {
+	m.cacheMissesTotal.WithLabelValues(cache).Inc()
+}
+
+func (m *Metrics) recordCacheRefresh(cache string) {
+	m.cacheAgeSeconds.WithLabelValues(cache).Set(0)
+}
+
+func (m *Metrics) recordRequisitionCreated() {
+	m.requisitionsCreatedTotal.Inc()
+}
+
+func (m *Metrics) recordNetSuiteAttempt() {
+	m.netsuiteCallAttemptsTotal.Inc()
+}
+
+func (m *Metrics) recordNetSuiteRetry() {
+	m.netsuiteCallRetriesTotal.Inc()
+}
+
+func (m *Metrics) recordNetSuiteTimeout() {
+	m.netsuiteCallTimeoutsTotal.Inc()
+}
+
+// handleHealthz is pure liveness: if the mux is serving requests at all,
+// this returns 200.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, "ok")
+}
+
+// handleReadyz returns 503 until the employee cache has been primed at
+// least once and NetSuite has answered successfully within the
+// configured readiness window - signalling a load balancer to hold
+// traffic while NetSuite is unreachable or the server just started.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	s.cacheMu.RLock()
+	primed := s.employeeCache != nil
+	s.cacheMu.RUnlock()
+
+	lastOK := s.metrics.lastNetSuiteSuccess()
+	window := s.config.Get().readyWindow()
+
+	ready := primed && !lastOK.IsZero() && time.Since(lastOK) < window
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"ready": ready})
+}
+
+// StartReadinessPinger pings NetSuite on a fixed interval so /readyz
+// stays accurate even when there's no user traffic to drive real calls.
+func (s *Server) StartReadinessPinger(interval time.Duration) {
+	if s.netsuite == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			_, err := s.netsuite.CallWithOptions(ctx, http.MethodGet, map[string]string{
+				"action": "ping",
+			}, nil, CacheRefreshCallOptions())
+			cancel()
+			if err != nil {
+				log.Printf("readiness ping failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CallOptions controls the retry and deadline behavior of a single
+// logical NetSuiteClient call (which may span several HTTP attempts).
+type CallOptions struct {
+	// Deadline bounds each individual attempt, not the call as a whole;
+	// the enclosing context passed to Call governs the overall budget.
+	Deadline time.Duration
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// RetryOnStatuses are HTTP statuses that should be retried. Network
+	// errors and per-attempt timeouts are always retryable.
+	RetryOnStatuses []int
+	// BackoffBase is multiplied by 2^attempt to produce the sleep
+	// between retries, plus jitter.
+	BackoffBase time.Duration
+}
+
+// DefaultCallOptions matches the interactive, user-facing request shape:
+// a generous per-attempt deadline with a couple of retries.
+func DefaultCallOptions() CallOptions {
+	return CallOptions{
+		Deadline:        15 * time.Second,
+		MaxAttempts:     3,
+		RetryOnStatuses: []int{429, 502, 503, 504},
+		BackoffBase:     250 * time.Millisecond,
+	}
+}
+
+// CacheRefreshCallOptions is used for the calls that refresh the
+// employees and locations caches and for the readiness ping: a tighter
+// per-attempt deadline and one more attempt than DefaultCallOptions.
+func CacheRefreshCallOptions() CallOptions {
+	return CallOptions{
+		Deadline:        5 * time.Second,
+		MaxAttempts:     4,
+		RetryOnStatuses: []int{429, 502, 503, 504},
+		BackoffBase:     200 * time.Millisecond,
+	}
+}
+
+func (o CallOptions) maxAttemptsOrDefault() int {
+	if o.MaxAttempts <= 0 {
+		return 1
+	}
+	return o.MaxAttempts
+}
+
+func (o CallOptions) deadlineOrDefault() time.Duration {
+	if o.Deadline <= 0 {
+		return 15 * time.Second
+	}
+	return o.Deadline
+}
+
+func (o CallOptions) backoffBaseOrDefault() time.Duration {
+	if o.BackoffBase <= 0 {
+		return 250 * time.Millisecond
+	}
+	return o.BackoffBase
+}
+
+func (o CallOptions) retriesStatus(status int) bool {
+	for _, s := range o.RetryOnStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// netsuiteStatusError wraps a non-2xx NetSuite response so callers (and
+// isRetryable) can branch on the status code without parsing strings.
+type netsuiteStatusError struct {
+	status int
+	body   string
+}
+
+func (e *netsuiteStatusError) Error() string {
+	return "NetSuite error (" + strconv.Itoa(e.status) + "): " + e.body
+}
+
+// netsuiteErrorStatus turns a Call error into the label used for the
+// netsuite_calls_total status dimension: the HTTP status if we got one,
+// "error" otherwise.
+func netsuiteErrorStatus(err error) string {
+	var statusErr *netsuiteStatusError
+	if errors.As(err, &statusErr) {
+		return strconv.Itoa(statusErr.status)
+	}
+	return "error"
+}
+
+// isRetryable reports whether err is worth another attempt: a network
+// error, a per-attempt timeout, or a status in opts.RetryOnStatuses.
+func isRetryable(err error, opts CallOptions) bool {
+	var statusErr *netsuiteStatusError
+	if errors.As(err, &statusErr) {
+		return opts.retriesStatus(statusErr.status)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return false
+}
+
+// backoffWithJitter returns base * 2^attempt plus up to base of jitter,
+// so concurrent retries after a shared outage don't all land at once.
+func backoffWithJitter(base time.Duration, attempt int) time.Duration {
+	backoff := base << attempt
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(base)))
+	if err != nil {
+		return backoff
+	}
+	return backoff + time.Duration(jitter.Int64())
+}
+
+// parseRetryAfter parses a Retry-After header given as a number of
+// seconds. NetSuite doesn't send the HTTP-date form, so that's all this
+// supports.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// deadlineTimer bounds a single attempt independently of the parent
+// context: C() closes once the deadline fires, so a caller can cancel
+// just that attempt's context while ctx continues to govern the call's
+// overall budget across retries.
+type deadlineTimer struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	cancelCh chan struct{}
+	fired    atomic.Bool
+}
+
+func newDeadlineTimer(d time.Duration) *deadlineTimer {
+	dt := &deadlineTimer{cancelCh: make(chan struct{})}
+	dt.timer = time.AfterFunc(d, func() {
+		dt.fired.Store(true)
+		close(dt.cancelCh)
+	})
+	return dt
+}
+
+func (dt *deadlineTimer) C() <-chan struct{} {
+	return dt.cancelCh
+}
+
+func (dt *deadlineTimer) expired() bool {
+	return dt.fired.Load()
+}
+
+func (dt *deadlineTimer) stop() {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	if dt.timer != nil {
+		dt.timer.Stop()
+	}
+}